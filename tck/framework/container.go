@@ -0,0 +1,37 @@
+package framework
+
+import (
+	"context"
+	"net"
+)
+
+// Container is a handle onto a single Docker container started for the
+// duration of one Testcase.
+type Container struct {
+	id       string
+	hostPort int
+
+	// stdout and stderr hold the tail of the container's log output, kept
+	// up to date by startTailingLogs, so a failing Testcase can show why
+	// the invoker rejected a request. Both are nil until tailing starts.
+	stdout, stderr *ringBuffer
+	stopLogTail    context.CancelFunc
+}
+
+// getFreePort asks the kernel for an ephemeral port that is free at the time
+// of the call, and returns it still bound by l. Since the port is released
+// the moment l is closed, under concurrent use the caller must hold l open
+// for as long as possible — ideally until right before the port is handed
+// to ContainerStart — so another goroutine's getFreePort can't be handed
+// back the very same port in the meantime.
+func getFreePort() (l *net.TCPListener, port int, err error) {
+	addr, err := net.ResolveTCPAddr("tcp", "localhost:0")
+	if err != nil {
+		return nil, 0, err
+	}
+	l, err = net.ListenTCP("tcp", addr)
+	if err != nil {
+		return nil, 0, err
+	}
+	return l, l.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,36 @@
+package framework
+
+import (
+	"context"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// logTailCapacity is how many trailing bytes of each of stdout/stderr are
+// kept in memory per container.
+const logTailCapacity = 64 * 1024
+
+// startTailingLogs begins following cont's stdout/stderr in the background
+// into cont.stdout/cont.stderr, so that a failing Testcase can report why
+// the invoker rejected its request. Tailing stops once cont.stopLogTail is
+// called, which the runner does as part of tearing the container down.
+func startTailingLogs(r *Runner, cont *Container) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cont.stopLogTail = cancel
+	cont.stdout = newRingBuffer(logTailCapacity)
+	cont.stderr = newRingBuffer(logTailCapacity)
+
+	reader, err := r.dockerClient.ContainerLogs(ctx, cont.id, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+	})
+	if err != nil {
+		return
+	}
+	go func() {
+		defer reader.Close()
+		_, _ = stdcopy.StdCopy(cont.stdout, cont.stderr, reader)
+	}()
+}
@@ -0,0 +1,192 @@
+package framework
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// Readiness describes how to decide that a just-started container is ready
+// to accept traffic, beyond the bare ability to open a TCP connection to its
+// published port. Exactly one of HTTPPath or LogRegexp is normally set; the
+// zero value means "TCP connect only".
+type Readiness struct {
+	// HTTPPath, when set, is polled with GET requests until the container
+	// replies with HTTPStatus (defaults to http.StatusOK).
+	HTTPPath   string
+	HTTPStatus int
+
+	// LogRegexp, when set, is matched against each line written to the
+	// container's stdout or stderr; a match means the container is ready.
+	LogRegexp *regexp.Regexp
+
+	// Timeout bounds how long to wait for readiness before giving up.
+	// Defaults to 10s.
+	Timeout time.Duration
+}
+
+func (r Readiness) timeout() time.Duration {
+	if r.Timeout == 0 {
+		return 10 * time.Second
+	}
+	return r.Timeout
+}
+
+func (r Readiness) httpStatus() int {
+	if r.HTTPStatus == 0 {
+		return http.StatusOK
+	}
+	return r.HTTPStatus
+}
+
+// waitUntilReady blocks until cont is deemed ready, and fails fast if the
+// Docker daemon reports the container died in the meantime. When readiness
+// declares an HTTP probe or a log regexp, that signal alone decides
+// readiness: a bare TCP dial only proves the listen socket is up, not that
+// the app behind it can actually serve a correct response, so it is not
+// allowed to win the race once a stronger check is configured. Only when
+// neither is set does waitUntilReady fall back to the TCP dial. It returns
+// once readiness is confirmed, or an error if readiness.timeout() elapses
+// first.
+func waitUntilReady(r *Runner, cont *Container, readiness Readiness) error {
+	ctx, cancel := context.WithTimeout(context.Background(), readiness.timeout())
+	defer cancel()
+
+	ready := make(chan struct{}, 1)
+	died := make(chan error, 1)
+
+	switch {
+	case readiness.HTTPPath != "":
+		go pollHTTP(ctx, cont.hostPort, readiness, ready)
+	case readiness.LogRegexp != nil:
+		go watchLogsForMatch(ctx, r, cont, readiness.LogRegexp, ready)
+	default:
+		go pollTCP(ctx, cont.hostPort, ready)
+	}
+	go watchContainerDied(ctx, r, cont, died)
+
+	select {
+	case <-ready:
+		return nil
+	case err := <-died:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("container %s did not become ready within %s", cont.id[:12], readiness.timeout())
+	}
+}
+
+// pollTCP dials hostPort with exponential backoff, honoring ctx's deadline,
+// and signals ready on the first successful connection.
+func pollTCP(ctx context.Context, hostPort int, ready chan<- struct{}) {
+	for delay := 10 * time.Millisecond; ; delay *= 2 {
+		conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", hostPort))
+		if err == nil {
+			conn.Close()
+			select {
+			case ready <- struct{}{}:
+			default:
+			}
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// pollHTTP repeatedly GETs readiness.HTTPPath until it gets back
+// readiness.httpStatus(), then signals ready.
+func pollHTTP(ctx context.Context, hostPort int, readiness Readiness, ready chan<- struct{}) {
+	url := fmt.Sprintf("http://localhost:%d%s", hostPort, readiness.HTTPPath)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		response, err := http.Get(url)
+		if err == nil {
+			response.Body.Close()
+			if response.StatusCode == readiness.httpStatus() {
+				select {
+				case ready <- struct{}{}:
+				default:
+				}
+				return
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// watchLogsForMatch tails the container's combined stdout/stderr and signals
+// ready as soon as a line matches logRegexp. Containers are started without
+// a tty (see setUpContainerWithReadiness), so the raw stream is multiplexed
+// per the moby stream protocol (an 8-byte stream-type/length header ahead of
+// each frame) and must be demultiplexed with stdcopy.StdCopy before it can
+// be scanned line by line, the same way startTailingLogs does.
+func watchLogsForMatch(ctx context.Context, r *Runner, cont *Container, logRegexp *regexp.Regexp, ready chan<- struct{}) {
+	reader, err := r.dockerClient.ContainerLogs(ctx, cont.id, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+	})
+	if err != nil {
+		return
+	}
+	defer reader.Close()
+
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		_, _ = stdcopy.StdCopy(pipeWriter, pipeWriter, reader)
+		pipeWriter.Close()
+	}()
+
+	scanner := bufio.NewScanner(pipeReader)
+	for scanner.Scan() {
+		if logRegexp.MatchString(scanner.Text()) {
+			select {
+			case ready <- struct{}{}:
+			default:
+			}
+			return
+		}
+	}
+}
+
+// watchContainerDied subscribes to the Docker daemon's event stream and
+// reports an error as soon as cont is reported dead or OOM-killed, so
+// waitUntilReady doesn't have to wait out its full timeout to report a
+// container that crashed on startup.
+func watchContainerDied(ctx context.Context, r *Runner, cont *Container, died chan<- error) {
+	eventFilters := filters.NewArgs()
+	eventFilters.Add("container", cont.id)
+	eventFilters.Add("event", "die")
+	eventFilters.Add("event", "oom")
+
+	messages, errs := r.dockerClient.Events(ctx, types.EventsOptions{Filters: eventFilters})
+	select {
+	case msg := <-messages:
+		died <- fmt.Errorf("container %s exited before becoming ready (action=%s)", cont.id[:12], msg.Action)
+	case err := <-errs:
+		if err != nil {
+			died <- fmt.Errorf("watching events for container %s: %v", cont.id[:12], err)
+		}
+	case <-ctx.Done():
+	}
+}
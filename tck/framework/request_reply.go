@@ -7,14 +7,17 @@ import (
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/go-connections/nat"
 	"io/ioutil"
-	"math"
 	"mime"
-	"net"
 	"net/http"
+	"regexp"
 	"strings"
-	"time"
 )
 
+// upperReadiness matches the ready line the "upper" fixture logs once its
+// HTTP server is actually accepting requests, giving the rr-* cases a real
+// readiness signal instead of the bare TCP dial.
+var upperReadiness = Readiness{LogRegexp: regexp.MustCompile(`(?i)listening on`)}
+
 var Request_reply = Suite{
 	Name:        "rr",
 	Description: "Request / Reply Interaction",
@@ -49,6 +52,7 @@ var Request_reply = Suite{
 			Name:        "rr-0001",
 			Description: "MUST NOT reply on paths other than / or methods other than POST",
 			Image:       "upper",
+			Readiness:   upperReadiness,
 			T: func(port int) {
 				response, err := http.Post(fmt.Sprintf("http://localhost:%d/bogus", port), "text/plain", strings.NewReader("hello"))
 				if err != nil {
@@ -80,6 +84,7 @@ var Request_reply = Suite{
 			Name:        "rr-0002",
 			Description: "MUST honor the Accept header",
 			Image:       "upper",
+			Readiness:   upperReadiness,
 			T: func(port int) {
 				req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/", port), strings.NewReader("hello"))
 				if err != nil {
@@ -145,6 +150,7 @@ var Request_reply = Suite{
 			Description: "SHOULD reply with 415 on unrecognized Content-Type",
 			Optional:    true,
 			Image:       "upper",
+			Readiness:   upperReadiness,
 			T: func(port int) {
 				req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/", port), strings.NewReader("hello"))
 				if err != nil {
@@ -165,6 +171,7 @@ var Request_reply = Suite{
 			Name:        "rr-0004",
 			Description: "MUST reply with 5xx on unmarshalling error",
 			Image:       "upper",
+			Readiness:   upperReadiness,
 			T: func(port int) {
 				req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/", port), strings.NewReader(`"hello`)) // malformed json
 				if err != nil {
@@ -186,6 +193,7 @@ var Request_reply = Suite{
 			Description: "SHOULD reply with 406 on inability to marshall back",
 			Optional:    true,
 			Image:       "upper",
+			Readiness:   upperReadiness,
 			T: func(port int) {
 				req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/", port), strings.NewReader(`hello`)) // malformed json
 				if err != nil {
@@ -205,20 +213,30 @@ var Request_reply = Suite{
 	},
 }
 
+// setUpContainerUsingPortEnvVar starts image with its port passed in the
+// $PORT env var, waiting for a plain TCP dial to succeed before handing the
+// container back. Cases that need a stronger readiness signal should set
+// Testcase.Readiness and go through setUpContainerWithReadiness instead.
 func setUpContainerUsingPortEnvVar(image string, r *Runner) (*Container, error) {
-	_, err := r.dockerClient.ImagePull(context.Background(), image, types.ImagePullOptions{})
-	if err != nil {
+	return setUpContainerWithReadiness(image, r, Readiness{})
+}
+
+// setUpContainerWithReadiness is the same as setUpContainerUsingPortEnvVar,
+// except that readiness decides when the container is ready before it is
+// handed back: an HTTP probe or log regexp, when set, replaces the bare TCP
+// dial rather than racing it (see waitUntilReady).
+func setUpContainerWithReadiness(image string, r *Runner, readiness Readiness) (*Container, error) {
+	if err := r.pullImage(image); err != nil {
 		return nil, err
 	}
-	hostPort, err := getFreePort()
+	listener, hostPort, err := getFreePort()
 	if err != nil {
 		return nil, err
 	}
+	defer listener.Close()
+
 	hostBinding := nat.PortBinding{HostIP: "0.0.0.0", HostPort: fmt.Sprintf("%d", hostPort)}
 	containerPort := nat.Port("4321")
-	if err != nil {
-		return nil, err
-	}
 	portBinding := nat.PortMap{containerPort: []nat.PortBinding{hostBinding}}
 	cont, err := r.dockerClient.ContainerCreate(context.Background(),
 		&container.Config{Image: image, ExposedPorts: nat.PortSet{containerPort: struct{}{}}, Env: []string{"PORT=4321"}},
@@ -228,23 +246,24 @@ func setUpContainerUsingPortEnvVar(image string, r *Runner) (*Container, error)
 	if err != nil {
 		return nil, err
 	}
+	result := &Container{id: cont.ID, hostPort: hostPort}
 
-	err = r.dockerClient.ContainerStart(context.Background(), cont.ID, types.ContainerStartOptions{})
-	if err != nil {
+	// Release the port right before handing it to Docker, keeping the
+	// window in which another goroutine's getFreePort could be handed the
+	// same port as small as possible.
+	listener.Close()
+	if err := r.dockerClient.ContainerStart(context.Background(), cont.ID, types.ContainerStartOptions{}); err != nil {
+		r.tearDownContainer(result)
 		return nil, err
 	}
-	for i := 0; i < 10; i = i + 1 {
-		_, err = net.Dial("tcp", fmt.Sprintf("localhost:%d", hostPort))
-		if err == nil {
-			break
-		}
-		time.Sleep(10 * time.Millisecond * time.Duration(math.Pow(2, float64(i))))
-	}
-	if err != nil {
+
+	startTailingLogs(r, result)
+	if err := waitUntilReady(r, result, readiness); err != nil {
+		// Don't leave a flaky-startup container running, nor its log tail
+		// goroutine tailing forever: every error path past ContainerCreate
+		// must tear down exactly what it started.
+		r.tearDownContainer(result)
 		return nil, err
 	}
-	// TODO: need to sleep some more. Find a more reliable way to diagnose a container as ready
-	time.Sleep(1000 * time.Millisecond)
-
-	return &Container{id: cont.ID, hostPort: hostPort}, nil
+	return result, nil
 }
@@ -0,0 +1,167 @@
+package framework
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+var Request_reply_cloudevents = Suite{
+	Name:        "rrce",
+	Description: "Request / Reply Interaction using CloudEvents",
+	Port:        8082,
+	Cases: []*Testcase{
+		{
+			Name:        "rrce-0001",
+			Description: "MUST accept and produce CloudEvents in binary mode, echoing ce-id and bumping ce-type",
+			Image:       "upper-ce",
+			T: func(port int) {
+				req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/", port), strings.NewReader(`"hello"`))
+				if err != nil {
+					panic(err)
+				}
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("ce-specversion", "1.0")
+				req.Header.Set("ce-id", "rrce-0001")
+				req.Header.Set("ce-source", "urn:tck:rrce")
+				req.Header.Set("ce-type", "tck.upper.requested")
+				response, err := http.DefaultClient.Do(req)
+				if err != nil {
+					panic(err)
+				}
+				defer response.Body.Close()
+				if response.StatusCode != http.StatusOK {
+					panic(fmt.Sprintf("Expected http status 200, got %d", response.StatusCode))
+				}
+				if response.Header.Get("ce-specversion") != "1.0" {
+					panic("Expected ce-specversion: 1.0 on the response")
+				}
+				if response.Header.Get("ce-id") != "rrce-0001" {
+					panic("Expected the response to echo back ce-id")
+				}
+				if response.Header.Get("ce-type") == "tck.upper.requested" {
+					panic("Expected the response ce-type to differ from the request's, not just echo it back")
+				}
+				result, err := ioutil.ReadAll(response.Body)
+				if err != nil {
+					panic(err)
+				}
+				if strings.TrimSpace(string(result)) != `"HELLO"` {
+					panic(`Expected body "HELLO", got ` + string(result))
+				}
+			},
+		},
+		{
+			Name:        "rrce-0002",
+			Description: "MUST accept structured mode and reply in binary mode, respecting datacontenttype",
+			Image:       "upper-ce",
+			T: func(port int) {
+				envelope := map[string]interface{}{
+					"specversion":     "1.0",
+					"id":              "rrce-0002",
+					"source":          "urn:tck:rrce",
+					"type":            "tck.upper.requested",
+					"datacontenttype": "application/json",
+					"data":            "hello",
+				}
+				body, err := json.Marshal(envelope)
+				if err != nil {
+					panic(err)
+				}
+				req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/", port), bytes.NewReader(body))
+				if err != nil {
+					panic(err)
+				}
+				req.Header.Set("Content-Type", "application/cloudevents+json")
+				response, err := http.DefaultClient.Do(req)
+				if err != nil {
+					panic(err)
+				}
+				defer response.Body.Close()
+				if response.StatusCode != http.StatusOK {
+					panic(fmt.Sprintf("Expected http status 200, got %d", response.StatusCode))
+				}
+				if response.Header.Get("ce-id") != "rrce-0002" {
+					panic("Expected the response to echo back ce-id")
+				}
+				mediaType, _, err := mime.ParseMediaType(response.Header.Get("Content-Type"))
+				if err != nil {
+					panic(fmt.Sprintf("Error parsing content-type: %v", err))
+				} else if mediaType != "application/json" {
+					panic(fmt.Sprintf("Expected response Content-Type to be application/json, got %v", mediaType))
+				}
+			},
+		},
+		{
+			Name:        "rrce-0003",
+			Description: "SHOULD accept structured mode and reply in structured mode, preserving extensions",
+			Optional:    true,
+			Image:       "upper-ce",
+			T: func(port int) {
+				envelope := map[string]interface{}{
+					"specversion":     "1.0",
+					"id":              "rrce-0003",
+					"source":          "urn:tck:rrce",
+					"type":            "tck.upper.requested",
+					"datacontenttype": "application/json",
+					"data":            "hello",
+					"tckextension":    "present",
+				}
+				body, err := json.Marshal(envelope)
+				if err != nil {
+					panic(err)
+				}
+				req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/", port), bytes.NewReader(body))
+				if err != nil {
+					panic(err)
+				}
+				req.Header.Set("Content-Type", "application/cloudevents+json")
+				req.Header.Set("Accept", "application/cloudevents+json")
+				response, err := http.DefaultClient.Do(req)
+				if err != nil {
+					panic(err)
+				}
+				defer response.Body.Close()
+				if response.StatusCode != http.StatusOK {
+					panic(fmt.Sprintf("Expected http status 200, got %d", response.StatusCode))
+				}
+				mediaType, _, err := mime.ParseMediaType(response.Header.Get("Content-Type"))
+				if err != nil {
+					panic(fmt.Sprintf("Error parsing content-type: %v", err))
+				} else if mediaType != "application/cloudevents+json" {
+					panic(fmt.Sprintf("Expected response Content-Type to be application/cloudevents+json, got %v", mediaType))
+				}
+				var reply map[string]interface{}
+				if err := json.NewDecoder(response.Body).Decode(&reply); err != nil {
+					panic(err)
+				}
+				if reply["tckextension"] != "present" {
+					panic("Expected the tckextension attribute to be preserved on the reply envelope")
+				}
+			},
+		},
+		{
+			Name:        "rrce-0004",
+			Description: "MUST reply with 400 (not 5xx) on a malformed CloudEvents envelope",
+			Image:       "upper-ce",
+			T: func(port int) {
+				req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/", port), strings.NewReader(`{"not-a":"cloudevent"`))
+				if err != nil {
+					panic(err)
+				}
+				req.Header.Set("Content-Type", "application/cloudevents+json")
+				response, err := http.DefaultClient.Do(req)
+				if err != nil {
+					panic(err)
+				}
+				if response.StatusCode != http.StatusBadRequest {
+					panic(fmt.Sprintf("Expected 400 http code for a malformed envelope, got %d", response.StatusCode))
+				}
+			},
+		},
+	},
+}
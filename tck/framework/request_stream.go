@@ -0,0 +1,220 @@
+package framework
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var Request_stream = Suite{
+	Name:        "rs",
+	Description: "Request / Stream Interaction",
+	Port:        8081,
+	Cases: []*Testcase{
+		{
+			Name:        "rs-0001",
+			Description: "MUST NOT reply on paths other than / or methods other than POST",
+			Image:       "upper-stream",
+			T: func(port int) {
+				response, err := http.Post(fmt.Sprintf("http://localhost:%d/bogus", port), "text/plain", strings.NewReader("hello\nworld"))
+				if err != nil {
+					panic(err)
+				}
+				if response.StatusCode == http.StatusOK {
+					panic("The function should only be exposed on /")
+				}
+
+				req, err := http.NewRequest("GET", fmt.Sprintf("http://localhost:%d/", port), nil)
+				if err != nil {
+					panic(err)
+				}
+				response, err = http.DefaultClient.Do(req)
+				if err != nil {
+					panic(err)
+				}
+				if response.StatusCode == http.StatusOK {
+					panic("The function should only accept POST")
+				}
+			},
+		},
+		{
+			Name:        "rs-0002",
+			Description: "MUST honor the Accept header, choosing between application/x-ndjson and text/event-stream",
+			Image:       "upper-stream",
+			T: func(port int) {
+				req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/", port), strings.NewReader("hello\nworld"))
+				if err != nil {
+					panic(err)
+				}
+				req.Header.Set("Content-Type", "text/plain")
+				req.Header.Set("Accept", "application/x-ndjson")
+				response, err := http.DefaultClient.Do(req)
+				if err != nil {
+					panic(err)
+				}
+				defer response.Body.Close()
+				if response.StatusCode != http.StatusOK {
+					panic(fmt.Sprintf(`Expected http status 200, got %d`, response.StatusCode))
+				}
+				mediaType, _, err := mime.ParseMediaType(response.Header.Get("Content-Type"))
+				if err != nil {
+					panic(fmt.Sprintf("Error parsing content-type: %v", err))
+				} else if mediaType != "application/x-ndjson" {
+					panic(fmt.Sprintf("Expected response Content-Type to be set to application/x-ndjson, got %v", mediaType))
+				}
+				chunks := readChunks(response.Body)
+				if len(chunks) != 2 {
+					panic(fmt.Sprintf("Expected 2 chunks, got %d: %v", len(chunks), chunks))
+				}
+
+				req, err = http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/", port), strings.NewReader("hello\nworld"))
+				if err != nil {
+					panic(err)
+				}
+				req.Header.Set("Content-Type", "text/plain")
+				req.Header.Set("Accept", "text/event-stream")
+				response, err = http.DefaultClient.Do(req)
+				if err != nil {
+					panic(err)
+				}
+				defer response.Body.Close()
+				mediaType, _, err = mime.ParseMediaType(response.Header.Get("Content-Type"))
+				if err != nil {
+					panic(fmt.Sprintf("Error parsing content-type: %v", err))
+				} else if mediaType != "text/event-stream" {
+					panic(fmt.Sprintf("Expected response Content-Type to be set to text/event-stream, got %v", mediaType))
+				}
+			},
+		},
+		{
+			Name:        "rs-0003",
+			Description: "SHOULD reply with 415 on unrecognized Content-Type",
+			Optional:    true,
+			Image:       "upper-stream",
+			T: func(port int) {
+				req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/", port), strings.NewReader("hello"))
+				if err != nil {
+					panic(err)
+				}
+				req.Header.Set("Content-Type", "bogus/content-type")
+				req.Header.Set("Accept", "application/x-ndjson")
+				response, err := http.DefaultClient.Do(req)
+				if err != nil {
+					panic(err)
+				}
+				if response.StatusCode != http.StatusUnsupportedMediaType {
+					panic(fmt.Sprintf("Expected 415 http code, got %d", response.StatusCode))
+				}
+			},
+		},
+		{
+			// NOTE: reader.Buffered() == 0 right after the first chunk is a
+			// timing-sensitive proxy for "the invoker didn't buffer the whole
+			// response" — on a slow scheduler or a very fast loopback, more
+			// bytes can land in the OS socket buffer (and get pulled into
+			// bufio.Reader on fill) before this check runs, even when the
+			// invoker paces correctly. It's Optional for that reason; a more
+			// robust version would have the invoker fixture itself report
+			// something less timing-dependent (e.g. the test drives a slow
+			// io.Reader on the request body and compares elapsed time against
+			// a payload large enough to exceed the OS socket buffers).
+			Name:        "rs-0004",
+			Description: "SHOULD NOT buffer the whole response when the reader applies backpressure",
+			Optional:    true,
+			Image:       "upper-stream",
+			T: func(port int) {
+				req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/", port), strings.NewReader("one\ntwo\nthree\nfour\nfive"))
+				if err != nil {
+					panic(err)
+				}
+				req.Header.Set("Content-Type", "text/plain")
+				req.Header.Set("Accept", "application/x-ndjson")
+				response, err := http.DefaultClient.Do(req)
+				if err != nil {
+					panic(err)
+				}
+				defer response.Body.Close()
+
+				reader := bufio.NewReader(response.Body)
+				first := readLine(reader)
+				if first == "" {
+					panic("Expected at least 2 chunks of output")
+				}
+				// Nothing beyond the first chunk should have arrived on the
+				// wire yet: if it has, the invoker already wrote the whole
+				// response up front instead of pacing it to the reader.
+				if buffered := reader.Buffered(); buffered > 0 {
+					panic(fmt.Sprintf("Invoker sent %d bytes beyond the first chunk before the client read anything, appears to buffer the whole response", buffered))
+				}
+				time.Sleep(200 * time.Millisecond)
+				second := readLine(reader)
+				if second == "" {
+					panic("Expected at least 2 chunks of output")
+				}
+			},
+		},
+		{
+			Name:        "rs-0005",
+			Description: "MUST terminate gracefully when the client disconnects mid-stream",
+			Image:       "upper-stream",
+			T: func(port int) {
+				req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/", port), strings.NewReader("one\ntwo\nthree"))
+				if err != nil {
+					panic(err)
+				}
+				req.Header.Set("Content-Type", "text/plain")
+				req.Header.Set("Accept", "application/x-ndjson")
+				response, err := http.DefaultClient.Do(req)
+				if err != nil {
+					panic(err)
+				}
+				reader := bufio.NewReader(response.Body)
+				readLine(reader)
+				// Disconnect before the stream is exhausted; the invoker must
+				// not hang or crash on the next request.
+				response.Body.Close()
+
+				req, err = http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/", port), strings.NewReader("still\nalive"))
+				if err != nil {
+					panic(err)
+				}
+				req.Header.Set("Content-Type", "text/plain")
+				req.Header.Set("Accept", "application/x-ndjson")
+				response, err = http.DefaultClient.Do(req)
+				if err != nil {
+					panic(err)
+				}
+				defer response.Body.Close()
+				if response.StatusCode != http.StatusOK {
+					panic(fmt.Sprintf("Expected http status 200 on the next request, got %d", response.StatusCode))
+				}
+			},
+		},
+	},
+}
+
+// readChunks reads r to completion and returns each ndjson line as a chunk.
+func readChunks(r io.Reader) []string {
+	var chunks []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			chunks = append(chunks, line)
+		}
+	}
+	return chunks
+}
+
+// readLine reads a single line without blocking past EOF, returning "" if
+// none was available.
+func readLine(r *bufio.Reader) string {
+	line, err := r.ReadString('\n')
+	if err != nil && line == "" {
+		return ""
+	}
+	return strings.TrimRight(line, "\n")
+}
@@ -0,0 +1,32 @@
+package framework
+
+import "sync"
+
+// ringBuffer is a fixed-capacity byte buffer that keeps only the most
+// recently written bytes, discarding the oldest ones once full. It
+// implements io.Writer so it can be used as a stdcopy.StdCopy destination.
+type ringBuffer struct {
+	mu       sync.Mutex
+	capacity int
+	buf      []byte
+}
+
+func newRingBuffer(capacity int) *ringBuffer {
+	return &ringBuffer{capacity: capacity}
+}
+
+func (b *ringBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > b.capacity {
+		b.buf = b.buf[len(b.buf)-b.capacity:]
+	}
+	return len(p), nil
+}
+
+func (b *ringBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.buf)
+}
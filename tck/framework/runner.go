@@ -0,0 +1,191 @@
+package framework
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// Parallelism caps how many Testcases run concurrently by default; override
+// per-Runner via Runner.Parallelism.
+var Parallelism = flag.Int("parallel", 4, "number of testcases to run concurrently")
+
+// Runner drives one or more Suites against a Docker daemon. Every unique
+// image referenced by the Suites is pulled exactly once up front, then
+// Testcases run concurrently across a worker pool, each starting and
+// tearing down its own container.
+type Runner struct {
+	dockerClient *client.Client
+
+	// Parallelism overrides the -parallel flag for this Runner. Zero means
+	// "use the flag's value".
+	Parallelism int
+
+	imagePulls sync.Map // image ref (string) -> *pullResult
+}
+
+// NewRunner creates a Runner talking to the Docker daemon configured through
+// the standard DOCKER_* environment variables.
+func NewRunner() (*Runner, error) {
+	cli, err := client.NewClientWithOpts(client.FromEnv)
+	if err != nil {
+		return nil, err
+	}
+	return &Runner{dockerClient: cli}, nil
+}
+
+// Run pulls every unique image referenced by suites, then runs all of their
+// Testcases concurrently, printing a PASS/FAIL line for each.
+func (r *Runner) Run(suites ...*Suite) {
+	var cases []*Testcase
+	var images []string
+	seenImages := map[string]bool{}
+	for _, suite := range suites {
+		for _, tc := range suite.Cases {
+			cases = append(cases, tc)
+			if !seenImages[tc.Image] {
+				seenImages[tc.Image] = true
+				images = append(images, tc.Image)
+			}
+		}
+	}
+	r.pullImages(images)
+
+	parallelism := r.Parallelism
+	if parallelism <= 0 {
+		parallelism = *Parallelism
+	}
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for _, tc := range cases {
+		tc := tc
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			r.runCase(tc)
+		}()
+	}
+	wg.Wait()
+}
+
+// pullImages pulls every one of images concurrently and waits for all pulls
+// to finish, so that no Testcase blocks a worker waiting on a pull another
+// worker already kicked off.
+func (r *Runner) pullImages(images []string) {
+	var wg sync.WaitGroup
+	for _, image := range images {
+		image := image
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := r.pullImage(image); err != nil {
+				fmt.Printf("FAIL pulling %s: %v\n", image, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// pullResult is the sync.Map value guarding a single image's pull: done is
+// closed once the pull completes, and err holds its outcome.
+type pullResult struct {
+	done chan struct{}
+	err  error
+}
+
+// pullImage pulls image exactly once per Runner no matter how many
+// Testcases reference it or how many goroutines call pullImage
+// concurrently; later callers block on and share the first call's result.
+func (r *Runner) pullImage(image string) error {
+	v, loaded := r.imagePulls.LoadOrStore(image, &pullResult{done: make(chan struct{})})
+	pr := v.(*pullResult)
+	if loaded {
+		<-pr.done
+		return pr.err
+	}
+
+	reader, err := r.dockerClient.ImagePull(context.Background(), image, types.ImagePullOptions{})
+	if err == nil {
+		_, err = io.Copy(ioutil.Discard, reader)
+		reader.Close()
+	}
+	pr.err = err
+	close(pr.done)
+	return err
+}
+
+func (r *Runner) runCase(tc *Testcase) {
+	setUp := tc.SetUpContainer
+	if setUp == nil {
+		readiness := tc.Readiness
+		setUp = func(image string, r *Runner) (*Container, error) {
+			return setUpContainerWithReadiness(image, r, readiness)
+		}
+	}
+	cont, err := setUp(tc.Image, r)
+	if err != nil {
+		printResult("FAIL %s: could not start container: %v\n", tc.Name, err)
+		return
+	}
+	if tc.TearDownContainer != nil {
+		defer tc.TearDownContainer(cont, r)
+	} else {
+		defer r.tearDownContainer(cont)
+	}
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			printResult("FAIL %s: %v\n%s", tc.Name, rec, containerLogTail(cont))
+			return
+		}
+		printResult("PASS %s\n", tc.Name)
+	}()
+	tc.T(cont.hostPort)
+}
+
+// outputMu serializes result printing so that, under the -parallel worker
+// pool, one Testcase's PASS/FAIL line and log tail are never interleaved
+// with another's.
+var outputMu sync.Mutex
+
+// printResult formats and prints a single Testcase's result as one atomic
+// write, guarded by outputMu.
+func printResult(format string, args ...interface{}) {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	fmt.Printf(format, args...)
+}
+
+func (r *Runner) tearDownContainer(c *Container) {
+	if c.stopLogTail != nil {
+		c.stopLogTail()
+	}
+	_ = r.dockerClient.ContainerRemove(context.Background(), c.id, types.ContainerRemoveOptions{Force: true})
+}
+
+// containerLogTail renders whatever of the container's stdout/stderr is
+// still held in its ring buffers, so a panic such as "Expected http status
+// 200, got 500" comes with the invoker's own diagnostics attached.
+func containerLogTail(c *Container) string {
+	var b strings.Builder
+	if c.stdout != nil {
+		if out := c.stdout.String(); out != "" {
+			fmt.Fprintf(&b, "--- stdout (tail) ---\n%s\n", out)
+		}
+	}
+	if c.stderr != nil {
+		if errOut := c.stderr.String(); errOut != "" {
+			fmt.Fprintf(&b, "--- stderr (tail) ---\n%s\n", errOut)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,29 @@
+package framework
+
+// Suite groups the Testcases that exercise a single interaction style (e.g.
+// request/reply) against invoker images.
+type Suite struct {
+	Name        string
+	Description string
+	Port        int
+	Cases       []*Testcase
+}
+
+// Testcase is a single assertion made against an invoker image. Unless
+// SetUpContainer is set, the runner starts the container by exposing $PORT
+// to the invoker process, as documented by setUpContainerUsingPortEnvVar.
+type Testcase struct {
+	Name              string
+	Description       string
+	Optional          bool
+	Image             string
+	SetUpContainer    func(image string, r *Runner) (*Container, error)
+	TearDownContainer func(container *Container, runner *Runner)
+	T                 func(port int)
+
+	// Readiness overrides how the runner's default set-up,
+	// setUpContainerWithReadiness, decides the container is ready to receive
+	// traffic. The zero value falls back to a plain TCP dial. It is not used
+	// when SetUpContainer is set.
+	Readiness Readiness
+}